@@ -0,0 +1,84 @@
+// Package log wraps a zap.SugaredLogger with per-component debug gating
+// controlled by the DEBUG environment variable, so operators can turn on
+// verbose logging for a subsystem in production without flipping the global
+// log level.
+package log
+
+import (
+	"os"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Logger is a *zap.SugaredLogger whose Debug* methods are additionally
+// gated by the DEBUG environment variable, matched against the logger's
+// dotted component name (as built up through successive Named calls).
+type Logger struct {
+	*zap.SugaredLogger
+	z           *zap.Logger
+	name        string
+	pattern     debugPattern
+	debugActive bool
+}
+
+// New wraps an existing *zap.SugaredLogger as the root of a Logger tree. The
+// DEBUG pattern is read from the environment once, at call time.
+func New(l *zap.SugaredLogger) *Logger {
+	return newLogger(l, "", compileDebugPattern(os.Getenv("DEBUG")))
+}
+
+func newLogger(l *zap.SugaredLogger, name string, p debugPattern) *Logger {
+	return &Logger{
+		SugaredLogger: l,
+		z:             l.Desugar(),
+		name:          name,
+		pattern:       p,
+		debugActive:   p.matches(name),
+	}
+}
+
+// Named returns a child Logger named "parent.child" whose debug gate is
+// re-evaluated against the DEBUG pattern for the combined name.
+func (l *Logger) Named(name string) *Logger {
+	full := name
+	if len(l.name) > 0 {
+		full = l.name + "." + name
+	}
+	return newLogger(l.SugaredLogger.Named(name), full, l.pattern)
+}
+
+// debugEnabled reports whether both the component pattern and zap's own
+// level allow a debug line through, checked via zap.Logger.Check so the
+// common "debug is off" path never builds the sugared argument slice.
+func (l *Logger) debugEnabled() bool {
+	return l.debugActive && l.z.Check(zapcore.DebugLevel, "") != nil
+}
+
+func (l *Logger) Debug(args ...interface{}) {
+	if !l.debugEnabled() {
+		return
+	}
+	l.SugaredLogger.Debug(args...)
+}
+
+func (l *Logger) Debugf(template string, args ...interface{}) {
+	if !l.debugEnabled() {
+		return
+	}
+	l.SugaredLogger.Debugf(template, args...)
+}
+
+func (l *Logger) Debugln(args ...interface{}) {
+	if !l.debugEnabled() {
+		return
+	}
+	l.SugaredLogger.Debugln(args...)
+}
+
+func (l *Logger) Debugw(msg string, keysAndValues ...interface{}) {
+	if !l.debugEnabled() {
+		return
+	}
+	l.SugaredLogger.Debugw(msg, keysAndValues...)
+}
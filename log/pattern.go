@@ -0,0 +1,48 @@
+package log
+
+import (
+	"path"
+	"strings"
+)
+
+// debugPattern is a compiled DEBUG env value: a comma-separated list of glob
+// patterns matched against a component's dotted name, e.g.
+// DEBUG="web.*,db.subdomain,-web.static". A leading "-" negates a pattern.
+// Patterns are evaluated in order and the last one to match wins, so a
+// negated pattern later in the list excludes an earlier positive match.
+type debugPattern []debugRule
+
+type debugRule struct {
+	glob   string
+	negate bool
+}
+
+func compileDebugPattern(env string) debugPattern {
+	var p debugPattern
+	for _, part := range strings.Split(env, ",") {
+		part = strings.TrimSpace(part)
+		if len(part) == 0 {
+			continue
+		}
+		rule := debugRule{glob: part}
+		if strings.HasPrefix(part, "-") {
+			rule.negate = true
+			rule.glob = part[1:]
+		}
+		if len(rule.glob) == 0 {
+			continue
+		}
+		p = append(p, rule)
+	}
+	return p
+}
+
+func (p debugPattern) matches(name string) bool {
+	enabled := false
+	for _, rule := range p {
+		if ok, _ := path.Match(rule.glob, name); ok {
+			enabled = !rule.negate
+		}
+	}
+	return enabled
+}
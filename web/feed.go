@@ -0,0 +1,10 @@
+package web
+
+import "github.com/XANi/svcbox/web/feed"
+
+// RegisterFeed mounts atom.xml, rss.xml and sitemap.xml under path on
+// subdomain, rendering p's entries via the feed package. See feed.FeedProvider
+// and feed.FeedOptions for the rendering and caching behavior.
+func (b *WebBackend) RegisterFeed(subdomain, path string, p feed.FeedProvider, opts feed.FeedOptions) error {
+	return b.AddSubdomainRouter(subdomain, feed.NewHandler(path, p, opts))
+}
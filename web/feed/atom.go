@@ -0,0 +1,89 @@
+package feed
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"time"
+)
+
+type atomLink struct {
+	Rel  string `xml:"rel,attr,omitempty"`
+	Href string `xml:"href,attr"`
+	Type string `xml:"type,attr,omitempty"`
+}
+
+type atomPerson struct {
+	Name string `xml:"name"`
+}
+
+type atomEntry struct {
+	ID      string   `xml:"id"`
+	Title   string   `xml:"title"`
+	Link    atomLink `xml:"link"`
+	Updated string   `xml:"updated"`
+	Summary string   `xml:"summary,omitempty"`
+	Content string   `xml:"content,omitempty"`
+}
+
+type atomFeedXML struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	Title   string      `xml:"title"`
+	ID      string      `xml:"id"`
+	Updated string      `xml:"updated"`
+	Author  atomPerson  `xml:"author"`
+	Links   []atomLink  `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+// tagURI builds an RFC 4151 tag URI: tag:<domain>,<start-date>:<slug>.
+func tagURI(domain string, start time.Time, slug string) string {
+	return fmt.Sprintf("tag:%s,%s:%s", domain, start.Format("2006-01-02"), slug)
+}
+
+// renderAtom renders meta/entries as an Atom 1.0 feed. selfURL is used as
+// the feed's self link. updated is the max Updated across entries (or
+// meta.StartDate if there are none), so callers can reuse it for
+// Last-Modified without re-walking entries.
+func renderAtom(meta FeedMeta, entries []FeedEntry, selfURL string) (body []byte, updated time.Time, err error) {
+	updated = meta.StartDate
+	links := []atomLink{
+		{Rel: "self", Href: selfURL, Type: "application/atom+xml"},
+	}
+	if len(meta.Link) > 0 {
+		links = append(links, atomLink{Rel: "alternate", Href: meta.Link, Type: "text/html"})
+	}
+	axEntries := make([]atomEntry, 0, len(entries))
+	for _, e := range entries {
+		if e.Updated.After(updated) {
+			updated = e.Updated
+		}
+		axEntries = append(axEntries, atomEntry{
+			ID:      tagURI(meta.Domain, meta.StartDate, e.Slug),
+			Title:   e.Title,
+			Link:    atomLink{Href: e.Link},
+			Updated: e.Updated.UTC().Format(time.RFC3339),
+			Summary: e.Summary,
+			Content: e.Content,
+		})
+	}
+	af := atomFeedXML{
+		Title:   meta.Title,
+		ID:      tagURI(meta.Domain, meta.StartDate, "feed"),
+		Updated: updated.UTC().Format(time.RFC3339),
+		Author:  atomPerson{Name: meta.Author},
+		Links:   links,
+		Entries: axEntries,
+	}
+	marshaled, err := xml.MarshalIndent(af, "", "  ")
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("error marshaling atom feed: %s", err)
+	}
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	if len(meta.StylesheetURL) > 0 {
+		fmt.Fprintf(&buf, "<?xml-stylesheet type=\"text/xsl\" href=%q?>\n", meta.StylesheetURL)
+	}
+	buf.Write(marshaled)
+	return buf.Bytes(), updated, nil
+}
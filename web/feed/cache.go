@@ -0,0 +1,70 @@
+package feed
+
+import (
+	"hash/fnv"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// cacheEntry holds one rendered representation (atom, rss or sitemap) of a
+// feed for a specific provider version and request host.
+type cacheEntry struct {
+	body     []byte
+	gzipBody []byte
+	// etag and gzipETag are distinct because, per RFC 7232, an ETag
+	// identifies one specific representation of a resource; body and
+	// gzipBody are different byte representations of the same document.
+	etag     string
+	gzipETag string
+	expires  time.Time
+}
+
+// cache is a small in-memory store for rendered feed documents, keyed by
+// cacheKey. It exists so a popular feed isn't re-rendered (and Entries
+// re-queried) on every request.
+type cache struct {
+	mu      sync.Mutex
+	entries map[string]*cacheEntry
+}
+
+func newCache() *cache {
+	return &cache{entries: map[string]*cacheEntry{}}
+}
+
+// cacheKey hashes (kind, version, host) so the same provider serving
+// several hosts (e.g. via wildcard subdomain routing) gets one cache
+// entry per host, and a provider bump (new Version()) invalidates all of
+// them implicitly by no longer matching any stored key.
+func cacheKey(kind, version, host string) string {
+	h := fnv.New64a()
+	h.Write([]byte(kind))
+	h.Write([]byte{0})
+	h.Write([]byte(version))
+	h.Write([]byte{0})
+	h.Write([]byte(host))
+	return strconv.FormatUint(h.Sum64(), 16)
+}
+
+func (c *cache) get(key string) (*cacheEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	if time.Now().After(e.expires) {
+		// Reap it now rather than merely ignoring it, so a host/version
+		// combination that stops being requested doesn't linger in the
+		// map forever.
+		delete(c.entries, key)
+		return nil, false
+	}
+	return e, true
+}
+
+func (c *cache) set(key string, e *cacheEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[key] = e
+}
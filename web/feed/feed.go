@@ -0,0 +1,62 @@
+// Package feed renders Atom, RSS and XML sitemap syndication documents for
+// handlers mounted via WebBackend.RegisterFeed.
+package feed
+
+import (
+	"context"
+	"time"
+)
+
+// FeedEntry is a single syndicated item.
+type FeedEntry struct {
+	// Slug uniquely identifies the entry within its feed. It's used to
+	// build the entry's RFC 4151 tag URI and must be stable across calls.
+	Slug    string
+	Title   string
+	Link    string
+	Updated time.Time
+	Summary string
+	Content string
+}
+
+// FeedMeta describes the feed as a whole.
+type FeedMeta struct {
+	Title       string
+	Description string
+	// Link is the feed's home page, used as the Atom feed's alternate
+	// link and the sitemap's root URL.
+	Link string
+	// Domain is the authority used to build RFC 4151 tag URIs
+	// (tag:<Domain>,<StartDate>:<slug>), typically the feed's hostname.
+	Domain string
+	// StartDate is the date component of the tag URI, conventionally the
+	// date Domain came under the publisher's control.
+	StartDate time.Time
+	// StylesheetURL, when set, is referenced via an <?xml-stylesheet?>
+	// processing instruction so the Atom feed renders nicely when opened
+	// directly in a browser.
+	StylesheetURL string
+	// Author is rendered as the feed-level atom:author. RFC 4287 requires
+	// one unless every entry carries its own, which FeedEntry has no field
+	// for, so this is always required.
+	Author string
+}
+
+// FeedProvider supplies the entries and metadata RegisterFeed renders into
+// Atom, RSS and sitemap documents.
+type FeedProvider interface {
+	// Entries returns the feed's items, most recent first.
+	Entries(ctx context.Context) ([]FeedEntry, error)
+	Meta() FeedMeta
+	// Version changes whenever Entries or Meta would render differently.
+	// It's used as the cache key and ETag so an unchanged feed can be
+	// served as 304 Not Modified instead of being re-rendered.
+	Version() string
+}
+
+// FeedOptions configures the routes RegisterFeed mounts.
+type FeedOptions struct {
+	// CacheFor is how long a rendered document is kept before Entries and
+	// Meta are consulted again for a given host. Defaults to 5 minutes.
+	CacheFor time.Duration
+}
@@ -0,0 +1,115 @@
+package feed
+
+import (
+	"bytes"
+	"compress/gzip"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// NewHandler returns an http.Handler serving atom.xml, rss.xml and
+// sitemap.xml under prefix (e.g. prefix "/blog" serves "/blog/atom.xml").
+// An empty prefix or "/" mounts them at the host root ("/atom.xml").
+// Rendered documents are cached in memory per p.Version() and request
+// host for opts.CacheFor, and served as 304 Not Modified on a matching
+// If-None-Match.
+func NewHandler(prefix string, p FeedProvider, opts FeedOptions) http.Handler {
+	if opts.CacheFor <= 0 {
+		opts.CacheFor = 5 * time.Minute
+	}
+	prefix = strings.TrimSuffix(prefix, "/")
+	if len(prefix) > 0 && !strings.HasPrefix(prefix, "/") {
+		prefix = "/" + prefix
+	}
+	f := &feedHandler{p: p, opts: opts, cache: newCache()}
+	mux := http.NewServeMux()
+	mux.HandleFunc(prefix+"/atom.xml", f.serveAtom)
+	mux.HandleFunc(prefix+"/rss.xml", f.serveRSS)
+	mux.HandleFunc(prefix+"/sitemap.xml", f.serveSitemap)
+	return mux
+}
+
+type feedHandler struct {
+	p     FeedProvider
+	opts  FeedOptions
+	cache *cache
+}
+
+func (f *feedHandler) serveAtom(w http.ResponseWriter, r *http.Request) {
+	self := selfURL(r)
+	f.serve(w, r, "atom", "application/atom+xml; charset=utf-8", func(meta FeedMeta, entries []FeedEntry) ([]byte, error) {
+		body, _, err := renderAtom(meta, entries, self)
+		return body, err
+	})
+}
+
+func (f *feedHandler) serveRSS(w http.ResponseWriter, r *http.Request) {
+	f.serve(w, r, "rss", "application/rss+xml; charset=utf-8", func(meta FeedMeta, entries []FeedEntry) ([]byte, error) {
+		return renderRSS(meta, entries)
+	})
+}
+
+func (f *feedHandler) serveSitemap(w http.ResponseWriter, r *http.Request) {
+	f.serve(w, r, "sitemap", "application/xml; charset=utf-8", func(_ FeedMeta, entries []FeedEntry) ([]byte, error) {
+		return renderSitemap(entries)
+	})
+}
+
+func selfURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host + r.URL.Path
+}
+
+// serve renders (or reuses a cached rendering of) one feed document and
+// writes it, honoring If-None-Match and Accept-Encoding: gzip.
+func (f *feedHandler) serve(w http.ResponseWriter, r *http.Request, kind, contentType string, render func(FeedMeta, []FeedEntry) ([]byte, error)) {
+	key := cacheKey(kind, f.p.Version(), r.Host)
+	entry, ok := f.cache.get(key)
+	if !ok {
+		entries, err := f.p.Entries(r.Context())
+		if err != nil {
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+		body, err := render(f.p.Meta(), entries)
+		if err != nil {
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+		var gz bytes.Buffer
+		gw := gzip.NewWriter(&gz)
+		gw.Write(body)
+		gw.Close()
+		entry = &cacheEntry{
+			body:     body,
+			gzipBody: gz.Bytes(),
+			etag:     `"` + key + `"`,
+			gzipETag: `"` + key + `-gzip"`,
+			expires:  time.Now().Add(f.opts.CacheFor),
+		}
+		f.cache.set(key, entry)
+	}
+
+	useGzip := strings.Contains(r.Header.Get("Accept-Encoding"), "gzip")
+	etag := entry.etag
+	if useGzip {
+		etag = entry.gzipETag
+	}
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Vary", "Accept-Encoding")
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	if useGzip {
+		w.Header().Set("Content-Encoding", "gzip")
+		w.Write(entry.gzipBody)
+		return
+	}
+	w.Write(entry.body)
+}
@@ -0,0 +1,64 @@
+package feed
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"time"
+)
+
+type rssItem struct {
+	Title       string `xml:"title"`
+	Link        string `xml:"link"`
+	GUID        string `xml:"guid"`
+	PubDate     string `xml:"pubDate,omitempty"`
+	Description string `xml:"description,omitempty"`
+}
+
+type rssChannel struct {
+	Title       string    `xml:"title"`
+	Link        string    `xml:"link"`
+	Description string    `xml:"description"`
+	Items       []rssItem `xml:"item"`
+}
+
+type rssFeedXML struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+// renderRSS renders meta/entries as an RSS 2.0 feed, reusing the same
+// RFC 4151 tag URIs as renderAtom for item GUIDs.
+func renderRSS(meta FeedMeta, entries []FeedEntry) ([]byte, error) {
+	items := make([]rssItem, 0, len(entries))
+	for _, e := range entries {
+		item := rssItem{
+			Title:       e.Title,
+			Link:        e.Link,
+			GUID:        tagURI(meta.Domain, meta.StartDate, e.Slug),
+			Description: e.Summary,
+		}
+		if !e.Updated.IsZero() {
+			item.PubDate = e.Updated.UTC().Format(time.RFC1123Z)
+		}
+		items = append(items, item)
+	}
+	rf := rssFeedXML{
+		Version: "2.0",
+		Channel: rssChannel{
+			Title:       meta.Title,
+			Link:        meta.Link,
+			Description: meta.Description,
+			Items:       items,
+		},
+	}
+	marshaled, err := xml.MarshalIndent(rf, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("error marshaling rss feed: %s", err)
+	}
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	buf.Write(marshaled)
+	return buf.Bytes(), nil
+}
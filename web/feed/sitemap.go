@@ -0,0 +1,38 @@
+package feed
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+)
+
+type sitemapURL struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod,omitempty"`
+}
+
+type sitemapURLSetXML struct {
+	XMLName xml.Name     `xml:"http://www.sitemaps.org/schemas/sitemap/0.9 urlset"`
+	URLs    []sitemapURL `xml:"url"`
+}
+
+// renderSitemap renders entries as a sitemap.xml document, one <url> per
+// entry with a <lastmod> when the entry has a non-zero Updated.
+func renderSitemap(entries []FeedEntry) ([]byte, error) {
+	urls := make([]sitemapURL, 0, len(entries))
+	for _, e := range entries {
+		u := sitemapURL{Loc: e.Link}
+		if !e.Updated.IsZero() {
+			u.LastMod = e.Updated.UTC().Format("2006-01-02")
+		}
+		urls = append(urls, u)
+	}
+	marshaled, err := xml.MarshalIndent(sitemapURLSetXML{URLs: urls}, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("error marshaling sitemap: %s", err)
+	}
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	buf.Write(marshaled)
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,228 @@
+package web
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+
+	"golang.org/x/net/idna"
+)
+
+type ctxKey int
+
+const (
+	ctxKeyHostPattern ctxKey = iota
+	ctxKeyTenant
+)
+
+// HostPatternFromContext returns the host pattern (as registered via
+// AddHostRouter/AddSubdomainRouter) that matched the current request.
+func HostPatternFromContext(ctx context.Context) (string, bool) {
+	p, ok := ctx.Value(ctxKeyHostPattern).(string)
+	return p, ok
+}
+
+// TenantFromContext returns the label captured by a leftmost wildcard
+// pattern (e.g. "acme" for host "acme.tenant.example.com" matched against
+// "*.tenant.example.com"). ok is false for exact-host and fallback matches.
+func TenantFromContext(ctx context.Context) (string, bool) {
+	t, ok := ctx.Value(ctxKeyTenant).(string)
+	return t, ok
+}
+
+// SubdomainRouter dispatches requests to a handler based on r.Host. It
+// supports, in order of precedence: exact host matches, leftmost-wildcard
+// matches (most specific suffix wins), legacy single-label subdomain
+// matches, an explicit "*" fallback, and finally the router's default
+// handler.
+type SubdomainRouter struct {
+	mu sync.RWMutex
+	// subdomains is the legacy routing table populated by
+	// AddSubdomainRouter: it matches only the leftmost label of the host,
+	// regardless of the rest of the domain.
+	subdomains map[string]http.Handler
+	// exact matches a full, normalized host.
+	exact map[string]http.Handler
+	// wildcards matches a full, normalized host with its leftmost label
+	// stripped, keyed by the fixed suffix (e.g. "tenant.example.com" for
+	// pattern "*.tenant.example.com").
+	wildcards map[string]http.Handler
+	// fallback is the handler registered for the bare "*" pattern.
+	fallback http.Handler
+	// def is the ultimate default handler, used when nothing else matches.
+	def http.Handler
+}
+
+func (s *SubdomainRouter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	host := normalizeHost(r.Host)
+
+	h, pattern, tenant := s.lookup(host)
+	if h == nil {
+		http.Error(w, "Not found", http.StatusNotFound)
+		return
+	}
+	s.serve(w, r, h, pattern, tenant)
+}
+
+// lookup resolves host to a handler under the read lock and returns,
+// so ServeHTTP never holds the lock across a downstream handler call —
+// important for long-lived handlers such as websocket-upgraded proxies,
+// which would otherwise stall every AddHostRouter/AddSubdomainRouter call
+// (and, transitively, every other request) for as long as they're open.
+func (s *SubdomainRouter) lookup(host string) (h http.Handler, pattern, tenant string) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if h, ok := s.exact[host]; ok {
+		return h, host, ""
+	}
+	if h, pattern, tenant, ok := s.matchWildcard(host); ok {
+		return h, pattern, tenant
+	}
+	if label := firstLabel(host); len(label) > 0 {
+		if h, ok := s.subdomains[label]; ok {
+			return h, label, ""
+		}
+	}
+	if s.fallback != nil {
+		return s.fallback, "*", ""
+	}
+	if s.def != nil {
+		return s.def, "", ""
+	}
+	return nil, "", ""
+}
+
+func (s *SubdomainRouter) serve(w http.ResponseWriter, r *http.Request, h http.Handler, pattern, tenant string) {
+	ctx := r.Context()
+	if len(pattern) > 0 {
+		ctx = context.WithValue(ctx, ctxKeyHostPattern, pattern)
+	}
+	if len(tenant) > 0 {
+		ctx = context.WithValue(ctx, ctxKeyTenant, tenant)
+	}
+	h.ServeHTTP(w, r.WithContext(ctx))
+}
+
+// matchWildcard returns the handler registered for the most specific (i.e.
+// longest) wildcard suffix matching host, along with the pattern it was
+// registered under and the label it captured.
+func (s *SubdomainRouter) matchWildcard(host string) (h http.Handler, pattern string, tenant string, ok bool) {
+	var bestSuffix string
+	for suffix, handler := range s.wildcards {
+		label, matches := matchWildcardSuffix(host, suffix)
+		if !matches {
+			continue
+		}
+		if h == nil || len(suffix) > len(bestSuffix) {
+			h, bestSuffix, tenant = handler, suffix, label
+		}
+	}
+	if h == nil {
+		return nil, "", "", false
+	}
+	return h, "*." + bestSuffix, tenant, true
+}
+
+// matchWildcardSuffix reports whether host is exactly one label plus
+// suffix, returning that leading label.
+func matchWildcardSuffix(host, suffix string) (label string, ok bool) {
+	if !strings.HasSuffix(host, "."+suffix) {
+		return "", false
+	}
+	label = strings.TrimSuffix(host, "."+suffix)
+	if len(label) == 0 || strings.Contains(label, ".") {
+		return "", false
+	}
+	return label, true
+}
+
+func firstLabel(host string) string {
+	if i := strings.IndexByte(host, '.'); i >= 0 {
+		return host[:i]
+	}
+	return host
+}
+
+// normalizeHost strips any port and IDN-normalizes r.Host so patterns and
+// requests are compared consistently regardless of case or unicode form.
+func normalizeHost(host string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return asciiLower(host)
+}
+
+// asciiLower lowercases host and, when it's a valid IDN, converts it to its
+// ASCII (punycode) form.
+func asciiLower(host string) string {
+	host = strings.ToLower(host)
+	if ascii, err := idna.ToASCII(host); err == nil {
+		host = ascii
+	}
+	return host
+}
+
+// normalizeHostPattern applies the same lowercasing/IDN normalization as
+// normalizeHost to a pattern passed to AddHostRouter, leaving a leading "*"
+// or "*." wildcard marker untouched so it matches however normalizeHost
+// renders the equivalent request host.
+func normalizeHostPattern(pattern string) string {
+	pattern = strings.TrimSuffix(pattern, ".")
+	switch {
+	case pattern == "*":
+		return pattern
+	case strings.HasPrefix(pattern, "*."):
+		return "*." + asciiLower(strings.TrimPrefix(pattern, "*."))
+	default:
+		return asciiLower(pattern)
+	}
+}
+
+// AddHostRouter registers h for hosts matching pattern, which is either an
+// exact hostname ("api.example.com"), a leftmost wildcard ("*.tenant.example.com",
+// matching exactly one label followed by that suffix), or the bare fallback
+// pattern "*". Conflicts within the same category (e.g. registering the same
+// exact host twice) are rejected; across categories, exact hosts win over
+// wildcards, the most specific (longest-suffix) wildcard wins over shorter
+// ones, and "*" is only used once nothing more specific matches.
+func (b *WebBackend) AddHostRouter(pattern string, h http.Handler) error {
+	pattern = normalizeHostPattern(pattern)
+	s := &b.subRouter
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch {
+	case pattern == "*":
+		if s.fallback != nil {
+			return fmt.Errorf("tried to register duplicate fallback pattern \"*\"")
+		}
+		s.fallback = h
+	case strings.HasPrefix(pattern, "*."):
+		suffix := strings.TrimPrefix(pattern, "*.")
+		if len(suffix) == 0 || strings.Contains(suffix, "*") {
+			return fmt.Errorf("invalid host pattern %q: expected a single leading wildcard label", pattern)
+		}
+		if s.wildcards == nil {
+			s.wildcards = map[string]http.Handler{}
+		}
+		if _, ok := s.wildcards[suffix]; ok {
+			return fmt.Errorf("tried to register duplicate host pattern %q", pattern)
+		}
+		s.wildcards[suffix] = h
+	case strings.Contains(pattern, "*"):
+		return fmt.Errorf("invalid host pattern %q: wildcard must be a single leading label (*.example.com) or the bare fallback pattern (*)", pattern)
+	default:
+		if s.exact == nil {
+			s.exact = map[string]http.Handler{}
+		}
+		if _, ok := s.exact[pattern]; ok {
+			return fmt.Errorf("tried to register duplicate host %q", pattern)
+		}
+		s.exact[pattern] = h
+	}
+	return nil
+}
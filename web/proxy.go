@@ -0,0 +1,302 @@
+package web
+
+import (
+	"crypto/tls"
+	"fmt"
+	"hash/fnv"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"sync/atomic"
+	"time"
+
+	"github.com/efigence/go-mon"
+)
+
+// LBPolicy selects how AddSubdomainProxy distributes requests across a
+// subdomain's backends.
+type LBPolicy int
+
+const (
+	RoundRobin LBPolicy = iota
+	LeastConn
+	IPHash
+)
+
+// ProxyBackend is a single upstream a reverse-proxied subdomain can send
+// traffic to.
+type ProxyBackend struct {
+	// Addr is the backend's host:port.
+	Addr string
+	// Scheme is "http" or "https", defaulting to "http".
+	Scheme string
+	// TLSConfig, when set, is used for the connection to this backend and
+	// implies Scheme "https".
+	TLSConfig *tls.Config
+}
+
+// ProxyOptions configures load balancing and health checking for
+// AddSubdomainProxy.
+type ProxyOptions struct {
+	Policy LBPolicy
+	// FailTimeout is how long a backend is kept marked down after MaxFails
+	// consecutive failures. Defaults to 10s.
+	FailTimeout time.Duration
+	// MaxFails is the number of consecutive dial errors or 5xx responses
+	// before a backend is marked down. Defaults to 3.
+	MaxFails int
+	// HealthCheckPath, when set, is polled every HealthCheckInterval
+	// (default 5s) to actively bring a backend up or down independently of
+	// live traffic.
+	HealthCheckPath     string
+	HealthCheckInterval time.Duration
+	// Websocket allows `Connection: Upgrade` requests to pass through to the
+	// backend. Defaults to false.
+	Websocket bool
+}
+
+// proxyBackend tracks the health state and reverse proxy for one backend of
+// a proxyPool.
+type proxyBackend struct {
+	backend ProxyBackend
+	target  *url.URL
+	proxy   *httputil.ReverseProxy
+	// healthClient is used for active health checks; it carries
+	// backend.TLSConfig so an https backend with a private CA or client
+	// cert can be probed the same way it's proxied to.
+	healthClient *http.Client
+
+	fails     int32 // atomic, consecutive failure count
+	downUntil atomic.Value
+	inflight  int32 // atomic, in-flight request count for LeastConn
+	// reportedDown is 1 once status has been reported StateCritical and 0
+	// once it's been reported StateOk again, so markUp/recordFailure only
+	// take bs.status's lock on an actual up/down transition instead of on
+	// every request.
+	reportedDown int32 // atomic
+
+	status *mon.Status
+}
+
+func (bs *proxyBackend) isDown() bool {
+	v := bs.downUntil.Load()
+	if v == nil {
+		return false
+	}
+	return time.Now().Before(v.(time.Time))
+}
+
+func (bs *proxyBackend) markUp() {
+	atomic.StoreInt32(&bs.fails, 0)
+	bs.downUntil.Store(time.Time{})
+	if atomic.CompareAndSwapInt32(&bs.reportedDown, 1, 0) {
+		bs.status.MustUpdate(mon.StateOk, "ok")
+	}
+}
+
+func (bs *proxyBackend) recordFailure(maxFails int, failTimeout time.Duration) {
+	if atomic.AddInt32(&bs.fails, 1) >= int32(maxFails) {
+		bs.downUntil.Store(time.Now().Add(failTimeout))
+		if atomic.CompareAndSwapInt32(&bs.reportedDown, 0, 1) {
+			bs.status.MustUpdate(mon.StateCritical, fmt.Sprintf("marked down after %d consecutive failures", maxFails))
+		}
+	}
+}
+
+// proxyPool is the http.Handler mounted on a subdomain by AddSubdomainProxy.
+type proxyPool struct {
+	opts      ProxyOptions
+	backends  []*proxyBackend
+	rrCounter uint64
+}
+
+// AddSubdomainProxy registers a load-balanced reverse proxy for subdomain,
+// distributing traffic across backends according to opts.Policy. Backend
+// health is tracked both passively (5xx/dial errors on live traffic) and,
+// when opts.HealthCheckPath is set, actively, and surfaced under
+// mon.GlobalStatus as "proxy.<subdomain>".
+func (b *WebBackend) AddSubdomainProxy(subdomain string, backends []ProxyBackend, opts ProxyOptions) error {
+	if len(backends) == 0 {
+		return fmt.Errorf("AddSubdomainProxy: at least one backend is required")
+	}
+	if opts.FailTimeout <= 0 {
+		opts.FailTimeout = 10 * time.Second
+	}
+	if opts.MaxFails <= 0 {
+		opts.MaxFails = 3
+	}
+	if len(opts.HealthCheckPath) > 0 && opts.HealthCheckInterval <= 0 {
+		opts.HealthCheckInterval = 5 * time.Second
+	}
+	status, err := mon.GlobalStatus.NewComponent("proxy." + subdomain)
+	if err != nil {
+		return err
+	}
+
+	pool := &proxyPool{opts: opts}
+	for i, be := range backends {
+		bs, err := newProxyBackend(pool, status, be, i)
+		if err != nil {
+			return err
+		}
+		pool.backends = append(pool.backends, bs)
+	}
+	if len(opts.HealthCheckPath) > 0 {
+		go pool.runActiveHealthChecks()
+	}
+	return b.AddSubdomainRouter(subdomain, pool)
+}
+
+func newProxyBackend(p *proxyPool, parentStatus *mon.Status, be ProxyBackend, idx int) (*proxyBackend, error) {
+	scheme := be.Scheme
+	if len(scheme) == 0 {
+		scheme = "http"
+	}
+	if be.TLSConfig != nil {
+		scheme = "https"
+	}
+	target := &url.URL{Scheme: scheme, Host: be.Addr}
+
+	status, err := parentStatus.NewComponent(fmt.Sprintf("backend%d-%s", idx, be.Addr))
+	if err != nil {
+		return nil, err
+	}
+	status.MustUpdate(mon.StateOk, "ok")
+
+	bs := &proxyBackend{backend: be, target: target, status: status}
+	bs.proxy = &httputil.ReverseProxy{
+		Director: func(r *http.Request) {
+			setForwardedHeaders(r)
+			r.URL.Scheme = target.Scheme
+			r.URL.Host = target.Host
+			r.Host = target.Host
+			if !p.opts.Websocket {
+				r.Header.Del("Upgrade")
+				r.Header.Del("Connection")
+			}
+		},
+		ModifyResponse: func(resp *http.Response) error {
+			if resp.StatusCode >= http.StatusInternalServerError {
+				bs.recordFailure(p.opts.MaxFails, p.opts.FailTimeout)
+			} else {
+				bs.markUp()
+			}
+			return nil
+		},
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			bs.recordFailure(p.opts.MaxFails, p.opts.FailTimeout)
+			http.Error(w, "Bad Gateway", http.StatusBadGateway)
+		},
+	}
+	if be.TLSConfig != nil {
+		bs.proxy.Transport = &http.Transport{TLSClientConfig: be.TLSConfig}
+		bs.healthClient = &http.Client{
+			Timeout:   p.opts.HealthCheckInterval,
+			Transport: &http.Transport{TLSClientConfig: be.TLSConfig},
+		}
+	}
+	return bs, nil
+}
+
+func setForwardedHeaders(r *http.Request) {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	if prior := r.Header.Get("X-Forwarded-For"); len(prior) > 0 {
+		host = prior + ", " + host
+	}
+	r.Header.Set("X-Forwarded-For", host)
+	proto := "http"
+	if r.TLS != nil {
+		proto = "https"
+	}
+	r.Header.Set("X-Forwarded-Proto", proto)
+	r.Header.Set("X-Forwarded-Host", r.Host)
+}
+
+func (p *proxyPool) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	bs := p.pick(r)
+	if bs == nil {
+		http.Error(w, "Bad Gateway", http.StatusBadGateway)
+		return
+	}
+	atomic.AddInt32(&bs.inflight, 1)
+	defer atomic.AddInt32(&bs.inflight, -1)
+	bs.proxy.ServeHTTP(w, r)
+}
+
+// pick selects a backend according to p.opts.Policy, preferring backends
+// that aren't marked down. When every backend is down it falls back to the
+// full set so traffic keeps flowing (and passive health checks can recover
+// it) rather than failing every request outright.
+func (p *proxyPool) pick(r *http.Request) *proxyBackend {
+	up := make([]*proxyBackend, 0, len(p.backends))
+	for _, bs := range p.backends {
+		if !bs.isDown() {
+			up = append(up, bs)
+		}
+	}
+	if len(up) == 0 {
+		up = p.backends
+	}
+	if len(up) == 0 {
+		return nil
+	}
+	switch p.opts.Policy {
+	case LeastConn:
+		best := up[0]
+		for _, bs := range up[1:] {
+			if atomic.LoadInt32(&bs.inflight) < atomic.LoadInt32(&best.inflight) {
+				best = bs
+			}
+		}
+		return best
+	case IPHash:
+		host, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			host = r.RemoteAddr
+		}
+		h := fnv.New32a()
+		h.Write([]byte(host))
+		return up[h.Sum32()%uint32(len(up))]
+	default: // RoundRobin
+		n := atomic.AddUint64(&p.rrCounter, 1)
+		return up[n%uint64(len(up))]
+	}
+}
+
+func (p *proxyPool) runActiveHealthChecks() {
+	ticker := time.NewTicker(p.opts.HealthCheckInterval)
+	defer ticker.Stop()
+	defaultClient := &http.Client{Timeout: p.opts.HealthCheckInterval}
+	for range ticker.C {
+		for _, bs := range p.backends {
+			go p.probe(defaultClient, bs)
+		}
+	}
+}
+
+// probe checks bs's health, using bs.healthClient (which carries
+// bs.backend.TLSConfig) when set so https backends with a private CA or
+// client cert are probed the same way they're proxied to.
+func (p *proxyPool) probe(defaultClient *http.Client, bs *proxyBackend) {
+	client := bs.healthClient
+	if client == nil {
+		client = defaultClient
+	}
+	u := *bs.target
+	u.Path = p.opts.HealthCheckPath
+	resp, err := client.Get(u.String())
+	if err != nil {
+		bs.recordFailure(p.opts.MaxFails, p.opts.FailTimeout)
+		return
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= http.StatusInternalServerError {
+		bs.recordFailure(p.opts.MaxFails, p.opts.FailTimeout)
+		return
+	}
+	bs.markUp()
+}
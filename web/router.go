@@ -2,10 +2,12 @@ package web
 
 import (
 	"fmt"
+	"github.com/XANi/svcbox/log"
 	"github.com/efigence/go-mon"
 	ginzap "github.com/gin-contrib/zap"
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
+	"golang.org/x/crypto/acme/autocert"
 	"html/template"
 	"io/fs"
 	"net"
@@ -16,32 +18,32 @@ import (
 )
 
 type WebBackend struct {
-	l         *zap.SugaredLogger
-	al        *zap.SugaredLogger
-	r         *gin.Engine
-	subRouter SubdomainRouter
-	cfg       *Config
+	l               *log.Logger
+	al              *log.Logger
+	r               *gin.Engine
+	subRouter       SubdomainRouter
+	cfg             *Config
+	autocertManager *autocert.Manager
 }
 
 type Config struct {
 	Logger       *zap.SugaredLogger `yaml:"-"`
 	AccessLogger *zap.SugaredLogger `yaml:"-"`
 	ListenAddr   string             `yaml:"listen_addr"`
-}
-
-type SubdomainRouter struct {
-	subdomains map[string]http.Handler
-	def        http.Handler
-}
-
-func (s SubdomainRouter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	domainParts := strings.Split(r.Host, ".")
-
-	if mux := s.subdomains[domainParts[0]]; mux != nil {
-		mux.ServeHTTP(w, r)
-	} else {
-		http.Error(w, "Not found", 404)
-	}
+	// TLSListenAddr, when set together with either AutocertHosts or
+	// TLSCertFile/TLSKeyFile, is used by RunHTTPS to serve HTTPS.
+	TLSListenAddr string `yaml:"tls_listen_addr"`
+	// AutocertHosts enables Let's Encrypt via golang.org/x/crypto/acme/autocert
+	// for the given hostnames. Mutually exclusive with TLSCertFile/TLSKeyFile.
+	AutocertHosts []string `yaml:"autocert_hosts"`
+	// AutocertCacheDir is where autocert persists issued certificates.
+	// Defaults to the current directory.
+	AutocertCacheDir string `yaml:"autocert_cache_dir"`
+	// TLSCertFile/TLSKeyFile configure a static certificate, reloaded on SIGHUP.
+	TLSCertFile string `yaml:"tls_cert_file"`
+	TLSKeyFile  string `yaml:"tls_key_file"`
+	// HSTS, when true, makes RunHTTPS set Strict-Transport-Security on responses.
+	HSTS bool `yaml:"hsts"`
 }
 
 func New(cfg Config, webFS fs.FS) (backend *WebBackend, err error) {
@@ -52,13 +54,28 @@ func New(cfg Config, webFS fs.FS) (backend *WebBackend, err error) {
 		panic("missing listen addr")
 	}
 	w := WebBackend{
-		l:         cfg.Logger,
-		al:        cfg.AccessLogger,
+		l:         log.New(cfg.Logger).Named("web"),
 		subRouter: SubdomainRouter{subdomains: map[string]http.Handler{}},
 		cfg:       &cfg,
 	}
-	if cfg.AccessLogger == nil {
-		w.al = w.l //.Named("accesslog")
+	if len(cfg.AutocertHosts) > 0 {
+		cacheDir := cfg.AutocertCacheDir
+		if len(cacheDir) == 0 {
+			cacheDir = "."
+		}
+		// Built once here, rather than as a side effect of RunHTTPS, so
+		// RunHTTPRedirect sees it deterministically regardless of which of
+		// the two is started first (they're meant to run concurrently).
+		w.autocertManager = &autocert.Manager{
+			Prompt:     autocert.AcceptTOS,
+			HostPolicy: autocert.HostWhitelist(cfg.AutocertHosts...),
+			Cache:      autocert.DirCache(cacheDir),
+		}
+	}
+	if cfg.AccessLogger != nil {
+		w.al = log.New(cfg.AccessLogger).Named("web").Named("access")
+	} else {
+		w.al = w.l.Named("access")
 	}
 	r := gin.New()
 	w.r = r
@@ -69,6 +86,10 @@ func New(cfg Config, webFS fs.FS) (backend *WebBackend, err error) {
 		return nil, fmt.Errorf("error loading templates: %s", err)
 	}
 	r.SetHTMLTemplate(t)
+	r.Use(func(c *gin.Context) {
+		w.al.Debugf("%s %s from %s", c.Request.Method, c.Request.URL.Path, c.Request.RemoteAddr)
+		c.Next()
+	})
 	// for zap logging
 	r.Use(ginzap.GinzapWithConfig(w.al.Desugar(), &ginzap.Config{
 		TimeFormat: time.RFC3339,
@@ -88,11 +109,13 @@ func New(cfg Config, webFS fs.FS) (backend *WebBackend, err error) {
 	// healthcheckHandler, haproxyStatus := mon.HandleHealthchecksHaproxy()
 	// r.GET("/_status/metrics", gin.WrapF(healthcheckHandler))
 
+	staticLog := w.l.Named("static")
 	httpFS := http.FileServer(http.FS(webFS))
 	r.GET("/s/*filepath", func(c *gin.Context) {
 		// content is embedded under static/ dir
 		p := strings.Replace(c.Request.URL.Path, "/s/", "/static/", -1)
 		c.Request.URL.Path = p
+		staticLog.Debugf("serving static file %s", p)
 		//c.Header("Cache-Control", "public, max-age=3600, immutable")
 		httpFS.ServeHTTP(c.Writer, c.Request)
 	})
@@ -110,18 +133,22 @@ func New(cfg Config, webFS fs.FS) (backend *WebBackend, err error) {
 	return &w, nil
 }
 
+// AddSubdomainRouter registers r for hosts whose leftmost label equals
+// subdomain, regardless of the rest of the domain. For full-hostname or
+// wildcard patterns, use AddHostRouter instead.
 func (b *WebBackend) AddSubdomainRouter(subdomain string, r http.Handler) error {
-	if _, ok := b.subRouter.subdomains[subdomain]; !ok {
-		b.subRouter.subdomains[subdomain] = r
-		return nil
-	} else {
+	b.subRouter.mu.Lock()
+	defer b.subRouter.mu.Unlock()
+	if _, ok := b.subRouter.subdomains[subdomain]; ok {
 		return fmt.Errorf("tried to register duplicate domain")
 	}
+	b.subRouter.subdomains[subdomain] = r
+	return nil
 }
 
 func (b *WebBackend) RunHTTP() error {
 	b.l.Infof("listening on %s", b.cfg.ListenAddr)
-	return http.ListenAndServe(b.cfg.ListenAddr, b.subRouter)
+	return http.ListenAndServe(b.cfg.ListenAddr, &b.subRouter)
 }
 
 func (b *WebBackend) RunUnix(file string, remove bool) error {
@@ -134,5 +161,5 @@ func (b *WebBackend) RunUnix(file string, remove bool) error {
 		defer os.Remove(file)
 	}
 
-	return http.Serve(listener, b.subRouter)
+	return http.Serve(listener, &b.subRouter)
 }
@@ -0,0 +1,106 @@
+package web
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// listenFDsStart is the first fd number systemd hands over via socket
+// activation; 0, 1 and 2 are stdin/stdout/stderr.
+const listenFDsStart = 3
+
+// ListenersFromEnv converts file descriptors passed by systemd socket
+// activation (LISTEN_FDS/LISTEN_PID/LISTEN_FDNAMES) into net.Listeners,
+// keyed by name when LISTEN_FDNAMES is set. It returns a nil map and no
+// error when no activation fds are present for this process, so callers can
+// fall back to their own listeners.
+func ListenersFromEnv() (map[string]net.Listener, error) {
+	pid, nfds, err := parseListenEnv()
+	if err != nil {
+		return nil, err
+	}
+	if nfds == 0 || pid != os.Getpid() {
+		return nil, nil
+	}
+	names := strings.Split(os.Getenv("LISTEN_FDNAMES"), ":")
+	listeners := make(map[string]net.Listener, nfds)
+	for i := 0; i < nfds; i++ {
+		fd := listenFDsStart + i
+		name := strconv.Itoa(fd)
+		if i < len(names) && len(names[i]) > 0 {
+			name = names[i]
+		}
+		f := os.NewFile(uintptr(fd), name)
+		l, err := net.FileListener(f)
+		if err != nil {
+			f.Close()
+			return nil, fmt.Errorf("error converting fd %d (%s) to a listener: %s", fd, name, err)
+		}
+		f.Close()
+		listeners[name] = l
+	}
+	return listeners, nil
+}
+
+func parseListenEnv() (pid int, nfds int, err error) {
+	nfdsStr := os.Getenv("LISTEN_FDS")
+	if len(nfdsStr) == 0 {
+		return 0, 0, nil
+	}
+	nfds, err = strconv.Atoi(nfdsStr)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid LISTEN_FDS %q: %s", nfdsStr, err)
+	}
+	pidStr := os.Getenv("LISTEN_PID")
+	if len(pidStr) == 0 {
+		return os.Getpid(), nfds, nil
+	}
+	pid, err = strconv.Atoi(pidStr)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid LISTEN_PID %q: %s", pidStr, err)
+	}
+	return pid, nfds, nil
+}
+
+// RunListenFD serves subRouter on every file descriptor handed over via
+// systemd socket activation (see ListenersFromEnv). When a name is passed,
+// only the matching named listener (LISTEN_FDNAMES) is used; an empty name
+// serves on all of them. It falls back to RunHTTP when no activation fds are
+// present, so a unit file without socket activation still works unmodified.
+func (b *WebBackend) RunListenFD(name string) error {
+	listeners, err := ListenersFromEnv()
+	if err != nil {
+		return err
+	}
+	if len(listeners) == 0 {
+		b.l.Infof("no systemd socket activation fds found, falling back to RunHTTP")
+		return b.RunHTTP()
+	}
+	if len(name) > 0 {
+		l, ok := listeners[name]
+		if !ok {
+			return fmt.Errorf("no activation listener named %q", name)
+		}
+		listeners = map[string]net.Listener{name: l}
+	}
+
+	errCh := make(chan error, len(listeners))
+	for lname, l := range listeners {
+		go func(lname string, l net.Listener) {
+			b.l.Infof("listening on activation fd %s (%s)", lname, l.Addr())
+			errCh <- http.Serve(l, &b.subRouter)
+		}(lname, l)
+	}
+	err = <-errCh
+	// Close every listener so the other Serve goroutines return instead of
+	// blocking forever; their errors land in errCh's buffer and are
+	// discarded since we already have the first error to report.
+	for _, l := range listeners {
+		l.Close()
+	}
+	return err
+}
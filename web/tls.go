@@ -0,0 +1,146 @@
+package web
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+)
+
+// modern, HTTP/2-friendly cipher suite list. TLS 1.3 suites are picked by the
+// runtime automatically and don't need to be listed here.
+var tlsCipherSuites = []uint16{
+	tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+	tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+	tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+	tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+	tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305,
+	tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305,
+}
+
+// certReloader serves a static keypair loaded from disk, reloading it on
+// SIGHUP so long-lived processes can pick up renewed certificates without a
+// restart.
+type certReloader struct {
+	certFile, keyFile string
+
+	mu   sync.RWMutex
+	cert *tls.Certificate
+}
+
+func (b *WebBackend) newCertReloader(certFile, keyFile string) (*certReloader, error) {
+	cr := &certReloader{certFile: certFile, keyFile: keyFile}
+	if err := cr.reload(); err != nil {
+		return nil, err
+	}
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, syscall.SIGHUP)
+	go func() {
+		for range sig {
+			if err := cr.reload(); err != nil {
+				b.l.Errorf("failed to reload TLS certificate on SIGHUP: %s", err)
+			} else {
+				b.l.Infof("reloaded TLS certificate %s", certFile)
+			}
+		}
+	}()
+	return cr, nil
+}
+
+func (cr *certReloader) reload() error {
+	cert, err := tls.LoadX509KeyPair(cr.certFile, cr.keyFile)
+	if err != nil {
+		return fmt.Errorf("error loading TLS keypair: %s", err)
+	}
+	cr.mu.Lock()
+	cr.cert = &cert
+	cr.mu.Unlock()
+	return nil
+}
+
+func (cr *certReloader) GetCertificate(_ *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	cr.mu.RLock()
+	defer cr.mu.RUnlock()
+	return cr.cert, nil
+}
+
+func (b *WebBackend) baseTLSConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion:               tls.VersionTLS12,
+		PreferServerCipherSuites: true,
+		CipherSuites:             tlsCipherSuites,
+		NextProtos:               []string{"h2", "http/1.1"},
+	}
+}
+
+// RunHTTPS starts a TLS listener on cfg.TLSListenAddr, sourcing certificates
+// either from cfg.AutocertHosts (via ACME, with certs cached in
+// cfg.AutocertCacheDir) or from cfg.TLSCertFile/cfg.TLSKeyFile, whichever is
+// configured. Explicit cert files are reloaded on SIGHUP.
+func (b *WebBackend) RunHTTPS() error {
+	if len(b.cfg.TLSListenAddr) == 0 {
+		panic("missing TLS listen addr")
+	}
+	tlsCfg := b.baseTLSConfig()
+	switch {
+	case len(b.cfg.AutocertHosts) > 0:
+		// b.autocertManager is built once in New, so RunHTTPRedirect (run
+		// concurrently, e.g. via `go b.RunHTTPRedirect()`) always sees it.
+		tlsCfg.GetCertificate = b.autocertManager.GetCertificate
+	case len(b.cfg.TLSCertFile) > 0 && len(b.cfg.TLSKeyFile) > 0:
+		cr, err := b.newCertReloader(b.cfg.TLSCertFile, b.cfg.TLSKeyFile)
+		if err != nil {
+			return err
+		}
+		tlsCfg.GetCertificate = cr.GetCertificate
+	default:
+		return fmt.Errorf("RunHTTPS requires either AutocertHosts or TLSCertFile/TLSKeyFile to be set")
+	}
+	srv := &http.Server{
+		Addr:      b.cfg.TLSListenAddr,
+		Handler:   b.tlsHandler(),
+		TLSConfig: tlsCfg,
+	}
+	b.l.Infof("listening on %s (TLS)", b.cfg.TLSListenAddr)
+	return srv.ListenAndServeTLS("", "")
+}
+
+// tlsHandler wraps subRouter with the HSTS header when enabled.
+func (b *WebBackend) tlsHandler() http.Handler {
+	if !b.cfg.HSTS {
+		return &b.subRouter
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Strict-Transport-Security", "max-age=31536000; includeSubDomains")
+		b.subRouter.ServeHTTP(w, r)
+	})
+}
+
+// RunHTTPRedirect serves plain HTTP on cfg.ListenAddr that redirects
+// everything to HTTPS, except for ACME HTTP-01 challenge requests which are
+// handled in place when AutocertHosts is configured. It's meant to be run
+// alongside RunHTTPS, e.g. `go b.RunHTTPRedirect()`.
+func (b *WebBackend) RunHTTPRedirect() error {
+	if len(b.cfg.ListenAddr) == 0 {
+		panic("missing listen addr")
+	}
+	var h http.Handler = http.HandlerFunc(b.redirectToHTTPS)
+	if b.autocertManager != nil {
+		h = b.autocertManager.HTTPHandler(h)
+	}
+	b.l.Infof("listening on %s (HTTP -> HTTPS redirect)", b.cfg.ListenAddr)
+	return http.ListenAndServe(b.cfg.ListenAddr, h)
+}
+
+func (b *WebBackend) redirectToHTTPS(w http.ResponseWriter, r *http.Request) {
+	host := r.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	target := "https://" + host + r.URL.RequestURI()
+	http.Redirect(w, r, target, http.StatusMovedPermanently)
+}